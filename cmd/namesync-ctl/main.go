@@ -0,0 +1,65 @@
+// Command namesync-ctl talks to a running namesync daemon over its local
+// control socket to query status or trigger pause/resume/sync-now/reload,
+// without having to grep logs or restart the daemon.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/hlandau/namesync/ctlproto"
+)
+
+var socketPath = flag.String("socket", "/var/run/namesync/namesync.sock", "path to the namesync control socket")
+
+func main() {
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: namesync-ctl [-socket path] <status|sync-now|pause|resume|reload>")
+		os.Exit(2)
+	}
+
+	res, err := send(*socketPath, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "namesync-ctl: "+err.Error())
+		os.Exit(1)
+	}
+
+	if !res.OK {
+		fmt.Fprintln(os.Stderr, "namesync-ctl: "+res.Error)
+		os.Exit(1)
+	}
+
+	if res.Status != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(res.Status)
+	}
+}
+
+func send(socketPath, command string) (ctlproto.Response, error) {
+	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		return ctlproto.Response{}, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(ctlproto.Request{Command: command}); err != nil {
+		return ctlproto.Response{}, err
+	}
+
+	var res ctlproto.Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return ctlproto.Response{}, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,29 @@
+// Package ctlproto defines the line-delimited JSON protocol spoken over
+// namesync's control socket (see server.Config.ControlSocketPath), so
+// that namesync-ctl can depend on the wire format without pulling in the
+// server package itself, which links every registered backend driver.
+package ctlproto
+
+// StatusInfo is the structured status returned by the "status" control
+// command, in place of the free-form string passed to
+// server.Config.StatusUpdateFunc.
+type StatusInfo struct {
+	Phase        string `json:"phase"`
+	BlockHeight  int64  `json:"block_height"`
+	LastSyncUnix int64  `json:"last_sync_unix"`
+	RowsWritten  int64  `json:"rows_written"`
+	Paused       bool   `json:"paused"`
+}
+
+// Request is a single control-socket request: one request, one response
+// per connection.
+type Request struct {
+	Command string `json:"command"`
+}
+
+// Response is the reply to a Request.
+type Response struct {
+	OK     bool        `json:"ok"`
+	Error  string      `json:"error,omitempty"`
+	Status *StatusInfo `json:"status,omitempty"`
+}
@@ -1,15 +1,18 @@
 package main
+import "os"
+import "os/signal"
+import "syscall"
 import "github.com/hlandau/degoutils/config"
 import "github.com/hlandau/degoutils/service"
 import "github.com/hlandau/namesync/server"
 
 func main() {
 	cfg := server.Config{}
-	config := config.Configurator{
+	configurator := config.Configurator{
 		ProgramName: "namesync",
 		ConfigFilePaths: []string{"$BIN/../etc/namesync.conf", "/etc/namesync/namesync.conf"},
 	}
-	config.ParseFatal(&cfg)
+	configurator.ParseFatal(&cfg)
 
 	service.Main(&service.Info{
 		Name: "namesync",
@@ -17,15 +20,31 @@ func main() {
 		DefaultChroot: service.EmptyChrootPath,
 		RunFunc: func(smgr service.Manager) error {
 			doneChan := make(chan error)
+			reloadChan := make(chan server.Config)
 
 			cfg.StatusUpdateFunc = func(status string) {
 				smgr.SetStatus("namesync: " + status)
 			}
 
+			// doReload re-parses the config file and hands the result to the
+			// running sync loop; it is used for both SIGHUP and a "reload"
+			// control-socket command.
+			doReload := func() {
+				newCfg := server.Config{}
+				if err := configurator.Parse(&newCfg); err != nil {
+					smgr.SetStatus("namesync: reload: config parse failed: " + err.Error())
+					return
+				}
+				newCfg.StatusUpdateFunc = cfg.StatusUpdateFunc
+				newCfg.ReloadRequestFunc = cfg.ReloadRequestFunc
+				reloadChan <- newCfg
+			}
+			cfg.ReloadRequestFunc = doReload
+
 			cfg.StatusUpdateFunc("starting")
 
 			go func() {
-				err := server.Run(cfg, func() error {
+				err := server.Run(cfg, smgr.StopChan(), reloadChan, func() error {
 					err := smgr.DropPrivileges()
 					if err != nil {
 						return err
@@ -38,21 +57,21 @@ func main() {
 				doneChan <- err
 			}()
 
-			select {
-				case <-smgr.StopChan():
-					// Stop was requested. Just return, everything in the daemon is transactional
-					// so we don't need to worry about a clean shutdown.
-					return nil
-
-				case err := <-doneChan:
-					// Daemon stopped spontaneously. Run() never returns nil, though.
-					if err == nil {
-						panic("unreachable")
-					}
-					return err
-			}
+			hupChan := make(chan os.Signal, 1)
+			signal.Notify(hupChan, syscall.SIGHUP)
+			go func() {
+				for range hupChan {
+					doReload()
+				}
+			}()
 
-			return nil
+			// server.Run watches smgr.StopChan() itself via an internal monitor
+			// goroutine and drains the in-flight batch before returning, so there
+			// is only one shutdown path to wait on here: doneChan. err is nil for
+			// a clean stop and non-nil for a spontaneous failure.
+			err := <-doneChan
+			signal.Stop(hupChan)
+			return err
 		},
 	})
 }
\ No newline at end of file
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// checkpoint is the cursor into the Namecoin namespace that an
+// incremental sync resumes from.
+type checkpoint struct {
+	Cursor      string
+	BlockHeight int64
+}
+
+// checkpointRowID is the single row namesync_state ever holds; namesync
+// only ever tracks one cursor into the namespace.
+const checkpointRowID = 1
+
+// Backend abstracts the destination datastore, so the sync loop itself
+// never has to know which SQL dialect it is talking to.
+type Backend interface {
+	// Open opens the datastore at dsn and ensures the schema namesync
+	// depends on exists.
+	Open(dsn string) (*sql.DB, error)
+
+	// BeginBatch starts the transaction a single name_scan batch is
+	// committed in.
+	BeginBatch(ctx context.Context, db *sql.DB) (*sql.Tx, error)
+
+	// UpsertName writes or updates a single name record within tx.
+	UpsertName(ctx context.Context, tx *sql.Tx, rec nameRecord) error
+
+	// DeleteName removes a name that has expired within tx.
+	DeleteName(ctx context.Context, tx *sql.Tx, name string) error
+
+	// CommitCheckpoint persists cp within tx, so it only becomes visible
+	// once the batch that produced it commits.
+	CommitCheckpoint(ctx context.Context, tx *sql.Tx, cp checkpoint) error
+
+	// LoadCheckpoint reads the last saved checkpoint, if any. ok is
+	// false if no checkpoint has been saved yet.
+	LoadCheckpoint(db *sql.DB) (cp checkpoint, ok bool, err error)
+}
+
+// BackendFactory constructs a fresh Backend instance.
+type BackendFactory func() Backend
+
+var (
+	backendsMu sync.Mutex
+	backends   = map[string]BackendFactory{}
+)
+
+// RegisterBackend makes a Backend available under name for
+// Config.Backend to select. It is meant to be called from the init()
+// function of a package implementing Backend.
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if factory == nil {
+		panic("server: RegisterBackend factory is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("server: RegisterBackend called twice for backend " + name)
+	}
+
+	backends[name] = factory
+}
+
+func getBackend(name string) (Backend, error) {
+	if name == "" {
+		name = "mysql"
+	}
+
+	backendsMu.Lock()
+	factory, ok := backends[name]
+	backendsMu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("namesync: unknown backend %q", name)
+	}
+
+	return factory(), nil
+}
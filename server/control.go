@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hlandau/namesync/ctlproto"
+)
+
+// control holds the knobs and status exposed over the control socket,
+// wired into the running sync loop. It is always allocated, independent
+// of whether a socket is actually listening, so the sync loop can treat
+// it uniformly.
+type control struct {
+	mu          sync.Mutex
+	phase       string
+	blockHeight int64
+	lastSync    int64
+	rowsWritten int64
+
+	paused  int32 // atomic bool
+	syncNow chan struct{}
+
+	reloadRequested chan struct{}
+}
+
+func newControl() *control {
+	return &control{
+		syncNow:         make(chan struct{}, 1),
+		reloadRequested: make(chan struct{}, 1),
+	}
+}
+
+func (c *control) setPhase(phase string) {
+	c.mu.Lock()
+	c.phase = phase
+	c.mu.Unlock()
+}
+
+func (c *control) recordSync(height int64, rows int64) {
+	c.mu.Lock()
+	c.blockHeight = height
+	c.lastSync = time.Now().Unix()
+	c.rowsWritten += rows
+	c.mu.Unlock()
+}
+
+func (c *control) isPaused() bool {
+	return atomic.LoadInt32(&c.paused) != 0
+}
+
+func (c *control) setPaused(paused bool) {
+	v := int32(0)
+	if paused {
+		v = 1
+	}
+	atomic.StoreInt32(&c.paused, v)
+}
+
+func (c *control) snapshot() ctlproto.StatusInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ctlproto.StatusInfo{
+		Phase:        c.phase,
+		BlockHeight:  c.blockHeight,
+		LastSyncUnix: c.lastSync,
+		RowsWritten:  c.rowsWritten,
+		Paused:       c.isPaused(),
+	}
+}
+
+func (c *control) requestSyncNow() {
+	select {
+	case c.syncNow <- struct{}{}:
+	default:
+	}
+}
+
+func (c *control) requestReload() {
+	select {
+	case c.reloadRequested <- struct{}{}:
+	default:
+	}
+}
+
+// startControlSocket removes any existing file at path (such as a stale
+// socket left by a previous run), listens on path, and serves control
+// requests until the returned listener is closed. It must be called before
+// DropPrivileges so the socket is created with the daemon's original
+// permissions.
+func startControlSocket(path string, ctl *control) (net.Listener, error) {
+	os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveControlConn(conn, ctl)
+		}
+	}()
+
+	return ln, nil
+}
+
+func serveControlConn(conn net.Conn, ctl *control) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var req ctlproto.Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		writeControlResponse(conn, ctlproto.Response{Error: "bad request: " + err.Error()})
+		return
+	}
+
+	switch req.Command {
+	case "status":
+		status := ctl.snapshot()
+		writeControlResponse(conn, ctlproto.Response{OK: true, Status: &status})
+
+	case "sync-now":
+		if ctl.isPaused() {
+			writeControlResponse(conn, ctlproto.Response{Error: "cannot sync-now while paused: resume first"})
+			break
+		}
+		ctl.requestSyncNow()
+		writeControlResponse(conn, ctlproto.Response{OK: true})
+
+	case "pause":
+		ctl.setPaused(true)
+		writeControlResponse(conn, ctlproto.Response{OK: true})
+
+	case "resume":
+		ctl.setPaused(false)
+		writeControlResponse(conn, ctlproto.Response{OK: true})
+
+	case "reload":
+		ctl.requestReload()
+		writeControlResponse(conn, ctlproto.Response{OK: true})
+
+	default:
+		writeControlResponse(conn, ctlproto.Response{Error: "unknown command: " + req.Command})
+	}
+}
+
+func writeControlResponse(conn net.Conn, res ctlproto.Response) {
+	enc := json.NewEncoder(conn)
+	enc.Encode(res)
+}
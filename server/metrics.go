@@ -0,0 +1,149 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds the Prometheus collectors namesync exposes on
+// Config.MetricsAddr, plus the readiness flag behind /readyz.
+type metrics struct {
+	registry *prometheus.Registry
+
+	namesProcessed prometheus.Counter
+	upserts        prometheus.Counter
+	deletes        prometheus.Counter
+	tipHeight      prometheus.Gauge
+	lastSyncUnix   prometheus.Gauge
+	syncLagBlocks  prometheus.Gauge
+	txDuration     prometheus.Histogram
+	rpcErrors      *prometheus.CounterVec
+
+	ready int32 // atomic bool
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		namesProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "namesync",
+			Name:      "names_processed_total",
+			Help:      "Total number of Namecoin names processed.",
+		}),
+		upserts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "namesync",
+			Name:      "names_upserted_total",
+			Help:      "Total number of names inserted or updated.",
+		}),
+		deletes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "namesync",
+			Name:      "names_deleted_total",
+			Help:      "Total number of expired names deleted.",
+		}),
+		tipHeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "namesync",
+			Name:      "tip_height",
+			Help:      "Current Namecoin chain tip height as last observed.",
+		}),
+		lastSyncUnix: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "namesync",
+			Name:      "last_sync_unix_time",
+			Help:      "Unix timestamp of the last successful batch commit.",
+		}),
+		syncLagBlocks: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "namesync",
+			Name:      "sync_lag_blocks",
+			Help:      "Blocks between the chain tip and the last height namesync has fully synced.",
+		}),
+		txDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "namesync",
+			Name:      "sql_transaction_duration_seconds",
+			Help:      "Duration of each batch's SQL transaction.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		rpcErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "namesync",
+			Name:      "rpc_errors_total",
+			Help:      "Total number of Namecoin RPC errors, by error code.",
+		}, []string{"code"}),
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m.namesProcessed, m.upserts, m.deletes, m.tipHeight, m.lastSyncUnix, m.syncLagBlocks, m.txDuration, m.rpcErrors)
+	m.registry = reg
+
+	return m
+}
+
+func (m *metrics) setReady(ready bool) {
+	v := int32(0)
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&m.ready, v)
+}
+
+func (m *metrics) isReady() bool {
+	return atomic.LoadInt32(&m.ready) != 0
+}
+
+func (m *metrics) recordBatch(upserted, deleted int, duration time.Duration) {
+	m.namesProcessed.Add(float64(upserted + deleted))
+	m.upserts.Add(float64(upserted))
+	m.deletes.Add(float64(deleted))
+	m.txDuration.Observe(duration.Seconds())
+}
+
+func (m *metrics) recordSync(syncedHeight, tipHeight int64) {
+	m.tipHeight.Set(float64(tipHeight))
+	m.lastSyncUnix.Set(float64(time.Now().Unix()))
+
+	lag := tipHeight - syncedHeight
+	if lag < 0 {
+		lag = 0
+	}
+	m.syncLagBlocks.Set(float64(lag))
+}
+
+func (m *metrics) recordRPCError(code int) {
+	m.rpcErrors.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// recordRPCErr records err against m if it is an *rpcError; it is a
+// no-op for any other error, including nil.
+func recordRPCErr(m *metrics, err error) {
+	if rerr, ok := err.(*rpcError); ok {
+		m.recordRPCError(rerr.Code)
+	}
+}
+
+// startMetricsServer listens on addr and serves /metrics, /healthz and
+// /readyz until the returned listener is closed.
+func startMetricsServer(addr string, m *metrics) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !m.isReady() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	go http.Serve(ln, mux)
+
+	return ln, nil
+}
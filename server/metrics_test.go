@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetricsRecordSyncComputesLagFromChainTip(t *testing.T) {
+	m := newMetrics()
+	m.recordSync(90, 100)
+
+	var metric dto.Metric
+	if err := m.syncLagBlocks.Write(&metric); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := metric.GetGauge().GetValue(); got != 10 {
+		t.Fatalf("sync_lag_blocks = %v, want 10", got)
+	}
+}
+
+func TestMetricsRecordSyncClampsNegativeLag(t *testing.T) {
+	m := newMetrics()
+	m.recordSync(100, 90)
+
+	var metric dto.Metric
+	if err := m.syncLagBlocks.Write(&metric); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := metric.GetGauge().GetValue(); got != 0 {
+		t.Fatalf("sync_lag_blocks = %v, want 0", got)
+	}
+}
+
+func TestRunOneScanSetsReadyAfterFirstBatch(t *testing.T) {
+	srv := newFakeRPCServer([]nameRecord{{Name: "d/test", Value: "v", ExpiresIn: 1000}}, nil)
+	defer srv.Close()
+
+	backend := sqliteBackend{}
+	db, err := backend.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	rpc := newRPCClient(srv.URL, "", "")
+	cfg := &Config{BatchSize: 10}
+	m := newMetrics()
+
+	if m.isReady() {
+		t.Fatal("expected not ready before any batch has committed")
+	}
+
+	stopping := make(chan struct{})
+	if _, _, err := runOneScan(context.Background(), backend, db, rpc, cfg, checkpoint{}, m, stopping); err != nil {
+		t.Fatalf("runOneScan: %v", err)
+	}
+
+	if !m.isReady() {
+		t.Fatal("expected ready after the first batch committed")
+	}
+}
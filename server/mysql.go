@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	RegisterBackend("mysql", func() Backend { return mysqlBackend{} })
+}
+
+// mysqlBackend is the original namesync behavior: a MySQL/MariaDB
+// destination using INSERT ... ON DUPLICATE KEY UPDATE.
+type mysqlBackend struct{}
+
+func (mysqlBackend) Open(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := mysqlEnsureSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func mysqlEnsureSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS names (
+			name VARCHAR(255) NOT NULL PRIMARY KEY,
+			value TEXT NOT NULL,
+			expires_in INT NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS namesync_state (
+			id INT NOT NULL PRIMARY KEY,
+			cursor VARCHAR(255) NOT NULL,
+			block_height BIGINT NOT NULL,
+			updated_at BIGINT NOT NULL
+		)
+	`)
+	return err
+}
+
+func (mysqlBackend) BeginBatch(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	return db.BeginTx(ctx, nil)
+}
+
+func (mysqlBackend) UpsertName(ctx context.Context, tx *sql.Tx, rec nameRecord) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO names (name, value, expires_in) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE value = VALUES(value), expires_in = VALUES(expires_in)
+	`, rec.Name, rec.Value, rec.ExpiresIn)
+	return err
+}
+
+func (mysqlBackend) DeleteName(ctx context.Context, tx *sql.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM names WHERE name = ?`, name)
+	return err
+}
+
+func (mysqlBackend) CommitCheckpoint(ctx context.Context, tx *sql.Tx, cp checkpoint) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO namesync_state (id, cursor, block_height, updated_at) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE cursor = VALUES(cursor), block_height = VALUES(block_height), updated_at = VALUES(updated_at)
+	`, checkpointRowID, cp.Cursor, cp.BlockHeight, time.Now().Unix())
+	return err
+}
+
+func (mysqlBackend) LoadCheckpoint(db *sql.DB) (checkpoint, bool, error) {
+	var cp checkpoint
+
+	row := db.QueryRow(`SELECT cursor, block_height FROM namesync_state WHERE id = ?`, checkpointRowID)
+	err := row.Scan(&cp.Cursor, &cp.BlockHeight)
+	if err == sql.ErrNoRows {
+		return checkpoint{}, false, nil
+	}
+	if err != nil {
+		return checkpoint{}, false, err
+	}
+
+	return cp, true, nil
+}
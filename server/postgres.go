@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func init() {
+	RegisterBackend("postgres", func() Backend { return postgresBackend{} })
+}
+
+// postgresBackend targets PostgreSQL, using INSERT ... ON CONFLICT DO
+// UPDATE in place of MySQL's ON DUPLICATE KEY UPDATE.
+type postgresBackend struct{}
+
+func (postgresBackend) Open(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := postgresEnsureSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func postgresEnsureSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS names (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			expires_in INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS namesync_state (
+			id INTEGER PRIMARY KEY,
+			cursor TEXT NOT NULL,
+			block_height BIGINT NOT NULL,
+			updated_at BIGINT NOT NULL
+		)
+	`)
+	return err
+}
+
+func (postgresBackend) BeginBatch(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	return db.BeginTx(ctx, nil)
+}
+
+func (postgresBackend) UpsertName(ctx context.Context, tx *sql.Tx, rec nameRecord) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO names (name, value, expires_in) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value, expires_in = EXCLUDED.expires_in
+	`, rec.Name, rec.Value, rec.ExpiresIn)
+	return err
+}
+
+func (postgresBackend) DeleteName(ctx context.Context, tx *sql.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM names WHERE name = $1`, name)
+	return err
+}
+
+func (postgresBackend) CommitCheckpoint(ctx context.Context, tx *sql.Tx, cp checkpoint) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO namesync_state (id, cursor, block_height, updated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET cursor = EXCLUDED.cursor, block_height = EXCLUDED.block_height, updated_at = EXCLUDED.updated_at
+	`, checkpointRowID, cp.Cursor, cp.BlockHeight, time.Now().Unix())
+	return err
+}
+
+func (postgresBackend) LoadCheckpoint(db *sql.DB) (checkpoint, bool, error) {
+	var cp checkpoint
+
+	row := db.QueryRow(`SELECT cursor, block_height FROM namesync_state WHERE id = $1`, checkpointRowID)
+	err := row.Scan(&cp.Cursor, &cp.BlockHeight)
+	if err == sql.ErrNoRows {
+		return checkpoint{}, false, nil
+	}
+	if err != nil {
+		return checkpoint{}, false, err
+	}
+
+	return cp, true, nil
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// applyReload computes the config that should take effect after a reload
+// request, and which top-level pieces of it actually changed. It refuses
+// changes to fields that cannot safely be applied without a restart.
+func applyReload(old, new Config) (merged Config, changed []string, err error) {
+	if new.Chroot != old.Chroot {
+		return old, nil, fmt.Errorf("chroot cannot be changed by reload, restart required")
+	}
+	if new.UID != old.UID {
+		return old, nil, fmt.Errorf("uid cannot be changed by reload, restart required")
+	}
+	if new.ControlSocketPath != old.ControlSocketPath {
+		return old, nil, fmt.Errorf("control socket path cannot be changed by reload, restart required")
+	}
+	if new.MetricsAddr != old.MetricsAddr {
+		return old, nil, fmt.Errorf("metrics address cannot be changed by reload, restart required")
+	}
+
+	merged = old
+
+	if new.Backend != old.Backend || new.SQLDSN != old.SQLDSN {
+		merged.Backend = new.Backend
+		merged.SQLDSN = new.SQLDSN
+		changed = append(changed, "Database")
+	}
+
+	if new.RPCAddress != old.RPCAddress || new.RPCUsername != old.RPCUsername || new.RPCPassword != old.RPCPassword {
+		merged.RPCAddress = new.RPCAddress
+		merged.RPCUsername = new.RPCUsername
+		merged.RPCPassword = new.RPCPassword
+		changed = append(changed, "RPC")
+	}
+
+	if new.BatchSize != old.BatchSize {
+		merged.BatchSize = new.BatchSize
+		changed = append(changed, "BatchSize")
+	}
+
+	if new.PollInterval != old.PollInterval {
+		merged.PollInterval = new.PollInterval
+		changed = append(changed, "PollInterval")
+	}
+
+	return merged, changed, nil
+}
+
+// handleReload applies newCfg on top of cfg, reopening the backend and/or
+// RPC client only if their parameters actually changed, and returns the
+// (possibly unchanged) config, backend and connections to keep using.
+func handleReload(cfg Config, newCfg Config, backend Backend, db *sql.DB, rpc *rpcClient) (Config, Backend, *sql.DB, *rpcClient) {
+	merged, changed, err := applyReload(cfg, newCfg)
+	if err != nil {
+		cfg.setStatus("reload rejected: " + err.Error())
+		return cfg, backend, db, rpc
+	}
+	if len(changed) == 0 {
+		cfg.setStatus("reload: no changes")
+		return merged, backend, db, rpc
+	}
+
+	for _, field := range changed {
+		switch field {
+		case "Database":
+			newBackend, err := getBackend(merged.Backend)
+			if err != nil {
+				cfg.setStatus("reload: " + err.Error())
+				return cfg, backend, db, rpc
+			}
+
+			newDB, err := newBackend.Open(merged.SQLDSN)
+			if err != nil {
+				cfg.setStatus("reload: failed to reopen database: " + err.Error())
+				return cfg, backend, db, rpc
+			}
+			db.Close()
+			backend, db = newBackend, newDB
+
+		case "RPC":
+			rpc = newRPCClient(merged.RPCAddress, merged.RPCUsername, merged.RPCPassword)
+		}
+	}
+
+	merged.setStatus("reloaded: applied " + strings.Join(changed, ", "))
+	return merged, backend, db, rpc
+}
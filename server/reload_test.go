@@ -0,0 +1,100 @@
+package server
+
+import "testing"
+
+func TestApplyReloadRejectsChrootChange(t *testing.T) {
+	old := Config{Chroot: "/var/lib/namesync"}
+	new := Config{Chroot: "/other"}
+
+	if _, _, err := applyReload(old, new); err == nil {
+		t.Fatal("applyReload: expected error for changed Chroot, got nil")
+	}
+}
+
+func TestApplyReloadRejectsUIDChange(t *testing.T) {
+	old := Config{UID: 100}
+	new := Config{UID: 200}
+
+	if _, _, err := applyReload(old, new); err == nil {
+		t.Fatal("applyReload: expected error for changed UID, got nil")
+	}
+}
+
+func TestApplyReloadRejectsControlSocketPathChange(t *testing.T) {
+	old := Config{ControlSocketPath: "/var/run/namesync/namesync.sock"}
+	new := Config{ControlSocketPath: "/other.sock"}
+
+	if _, _, err := applyReload(old, new); err == nil {
+		t.Fatal("applyReload: expected error for changed ControlSocketPath, got nil")
+	}
+}
+
+func TestApplyReloadRejectsMetricsAddrChange(t *testing.T) {
+	old := Config{MetricsAddr: ":9100"}
+	new := Config{MetricsAddr: ":9200"}
+
+	if _, _, err := applyReload(old, new); err == nil {
+		t.Fatal("applyReload: expected error for changed MetricsAddr, got nil")
+	}
+}
+
+func TestApplyReloadNoChanges(t *testing.T) {
+	old := Config{SQLDSN: "dsn", BatchSize: 100}
+
+	merged, changed, err := applyReload(old, old)
+	if err != nil {
+		t.Fatalf("applyReload: unexpected error: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("applyReload: expected no changes, got %v", changed)
+	}
+	if merged.SQLDSN != old.SQLDSN || merged.BatchSize != old.BatchSize {
+		t.Fatalf("applyReload: expected unchanged config, got %+v", merged)
+	}
+}
+
+func TestApplyReloadMergesChangedFields(t *testing.T) {
+	old := Config{
+		Backend:      "mysql",
+		SQLDSN:       "old-dsn",
+		RPCAddress:   "old-rpc",
+		BatchSize:    100,
+		PollInterval: 0,
+		Chroot:       "/var/lib/namesync",
+		UID:          1,
+	}
+	new := Config{
+		Backend:      "mysql",
+		SQLDSN:       "old-dsn",
+		RPCAddress:   "new-rpc",
+		BatchSize:    200,
+		PollInterval: 0,
+		Chroot:       "/var/lib/namesync",
+		UID:          1,
+	}
+
+	merged, changed, err := applyReload(old, new)
+	if err != nil {
+		t.Fatalf("applyReload: unexpected error: %v", err)
+	}
+
+	wantChanged := map[string]bool{"RPC": true, "BatchSize": true}
+	if len(changed) != len(wantChanged) {
+		t.Fatalf("applyReload: expected changes %v, got %v", wantChanged, changed)
+	}
+	for _, field := range changed {
+		if !wantChanged[field] {
+			t.Fatalf("applyReload: unexpected changed field %q", field)
+		}
+	}
+
+	if merged.RPCAddress != "new-rpc" {
+		t.Errorf("applyReload: RPCAddress = %q, want %q", merged.RPCAddress, "new-rpc")
+	}
+	if merged.BatchSize != 200 {
+		t.Errorf("applyReload: BatchSize = %d, want %d", merged.BatchSize, 200)
+	}
+	if merged.SQLDSN != "old-dsn" {
+		t.Errorf("applyReload: SQLDSN = %q, want unchanged %q", merged.SQLDSN, "old-dsn")
+	}
+}
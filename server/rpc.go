@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// rpcClient is a minimal JSON-RPC client for talking to a Namecoin Core
+// style node (the same RPC interface as the Namecoin Name Currency
+// extensions: name_scan, name_filter, getinfo, ...).
+type rpcClient struct {
+	endpoint string
+	user     string
+	pass     string
+	hc       *http.Client
+}
+
+func newRPCClient(endpoint, user, pass string) *rpcClient {
+	return &rpcClient{
+		endpoint: endpoint,
+		user:     user,
+		pass:     pass,
+		hc:       &http.Client{},
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+func (c *rpcClient) call(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	reqBody, err := json.Marshal(rpcRequest{
+		JSONRPC: "1.0",
+		ID:      "namesync",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.user, c.pass)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var rres rpcResponse
+	if err := json.NewDecoder(res.Body).Decode(&rres); err != nil {
+		return nil, err
+	}
+	if rres.Error != nil {
+		return nil, rres.Error
+	}
+
+	return rres.Result, nil
+}
+
+// nameRecord is a single entry as returned by name_scan/name_filter.
+type nameRecord struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// nameScan returns up to count names starting after start (the empty
+// string means "from the beginning of the namespace").
+func (c *rpcClient) nameScan(ctx context.Context, start string, count int) ([]nameRecord, error) {
+	raw, err := c.call(ctx, "name_scan", start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var recs []nameRecord
+	if err := json.Unmarshal(raw, &recs); err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// blockCount returns the current Namecoin chain tip height.
+func (c *rpcClient) blockCount(ctx context.Context) (int64, error) {
+	raw, err := c.call(ctx, "getblockcount")
+	if err != nil {
+		return 0, err
+	}
+
+	var height int64
+	if err := json.Unmarshal(raw, &height); err != nil {
+		return 0, err
+	}
+
+	return height, nil
+}
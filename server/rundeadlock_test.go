@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hlandau/namesync/ctlproto"
+)
+
+// dialControlSocketWithRetry dials path, retrying briefly while Run's
+// control-socket listener is still coming up.
+func dialControlSocketWithRetry(t *testing.T, path string) net.Conn {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			return conn
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("dialing control socket: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRunDispatchesReloadRequestAsynchronously guards against the
+// control-socket "reload" command deadlocking Run: ReloadRequestFunc must
+// be dispatched in its own goroutine, since the channel send it performs
+// is only ever drained by this same select loop.
+func TestRunDispatchesReloadRequestAsynchronously(t *testing.T) {
+	srv := newFakeRPCServer(nil, nil)
+	defer srv.Close()
+
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	cfg := Config{
+		Backend:           "sqlite",
+		SQLDSN:            ":memory:",
+		RPCAddress:        srv.URL,
+		BatchSize:         10,
+		PollInterval:      time.Hour,
+		ControlSocketPath: filepath.Join(t.TempDir(), "namesync.sock"),
+	}
+	// A ReloadRequestFunc that never returns on its own, standing in for
+	// namesync.go's doReload, which blocks sending on an unbuffered
+	// channel only Run's own select loop reads from.
+	cfg.ReloadRequestFunc = func() { <-blocked }
+
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(cfg, stop, make(chan Config), func() error { return nil })
+	}()
+
+	conn := dialControlSocketWithRetry(t, cfg.ControlSocketPath)
+	if err := json.NewEncoder(conn).Encode(ctlproto.Request{Command: "reload"}); err != nil {
+		t.Fatalf("sending reload command: %v", err)
+	}
+	var res ctlproto.Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		t.Fatalf("reading reload response: %v", err)
+	}
+	conn.Close()
+	if !res.OK {
+		t.Fatalf("reload command rejected: %s", res.Error)
+	}
+
+	close(stop)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after stop; reload dispatch likely blocked the select loop")
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFakeRPCServer serves name_scan (returning recs, and invoking
+// onNameScan if set, on every call) and getblockcount (always returning a
+// fixed height), enough to drive runOneScan in tests without a real
+// Namecoin node.
+func newFakeRPCServer(recs []nameRecord, onNameScan func()) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "name_scan":
+			if onNameScan != nil {
+				onNameScan()
+			}
+			result = recs
+		case "getblockcount":
+			result = 100
+		default:
+			http.Error(w, "unknown method "+req.Method, http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(rpcResponse{Result: data})
+	}))
+}
+
+func TestRunOneScanStopsAfterInFlightBatch(t *testing.T) {
+	stopping := make(chan struct{})
+	calls := 0
+
+	srv := newFakeRPCServer([]nameRecord{{Name: "d/test", Value: "v", ExpiresIn: 1000}}, func() {
+		calls++
+		if calls == 1 {
+			close(stopping)
+		}
+	})
+	defer srv.Close()
+
+	backend := sqliteBackend{}
+	db, err := backend.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	rpc := newRPCClient(srv.URL, "", "")
+	cfg := &Config{BatchSize: 1}
+	m := newMetrics()
+
+	_, rows, err := runOneScan(context.Background(), backend, db, rpc, cfg, checkpoint{}, m, stopping)
+	if err != nil {
+		t.Fatalf("runOneScan: %v", err)
+	}
+
+	// With BatchSize 1 and a name_scan that always returns one record,
+	// runOneScan would loop forever unless it stops checking for more
+	// work as soon as stopping fires between batches.
+	if calls != 1 {
+		t.Fatalf("expected exactly one name_scan call before stopping, got %d", calls)
+	}
+	if rows != 1 {
+		t.Fatalf("expected the in-flight batch to finish and commit, got rows=%d", rows)
+	}
+}
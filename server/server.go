@@ -0,0 +1,379 @@
+// Package server implements the namesync daemon: it walks the Namecoin
+// namespace over RPC and mirrors it into a SQL database.
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SyncMode selects how the namespace is walked.
+type SyncMode string
+
+const (
+	// SyncModeFull re-scans the entire Namecoin namespace on every run.
+	SyncModeFull SyncMode = "full"
+
+	// SyncModeIncremental resumes the name_scan walk from the saved
+	// checkpoint's cursor, so only names that sort after the last name
+	// committed are fetched; names before the cursor are not revisited
+	// until the next full rescan. It falls back to a full rescan if the
+	// checkpoint is missing or corrupt.
+	SyncModeIncremental SyncMode = "incremental"
+)
+
+// Config configures a namesync run.
+type Config struct {
+	SQLDSN string
+
+	// Backend selects the destination datastore driver ("mysql",
+	// "postgres", "sqlite"). Defaults to "mysql".
+	Backend string
+
+	RPCAddress string
+	RPCUsername string
+	RPCPassword string
+
+	// BatchSize is how many names are fetched and committed per
+	// name_scan call.
+	BatchSize int
+
+	// SyncMode is SyncModeFull or SyncModeIncremental. Defaults to
+	// SyncModeFull if empty.
+	SyncMode SyncMode
+
+	// PollInterval is how long to wait between batches once the
+	// namespace has been fully walked. Zero means "scan once and exit".
+	PollInterval time.Duration
+
+	// ResyncInterval, when nonzero, forces a full rescan at this
+	// interval even in incremental mode, to heal any drift.
+	ResyncInterval time.Duration
+
+	// ShutdownTimeout bounds how long Run waits, once stop fires, for the
+	// in-flight SQL transaction and RPC calls to finish on their own
+	// before it cancels them. Defaults to 30s.
+	ShutdownTimeout time.Duration
+
+	// Chroot and UID affect how the process drops privileges at startup
+	// and cannot be changed by a reload; Run rejects a reload that
+	// changes either.
+	Chroot string
+	UID    int
+
+	// ControlSocketPath, if set, is the path of a Unix-domain socket
+	// exposing status and control commands (status, sync-now, pause,
+	// resume, reload) to the namesync-ctl client. It is created before
+	// DropPrivileges.
+	ControlSocketPath string
+
+	// MetricsAddr, if set, is the address (host:port) to serve
+	// Prometheus metrics on /metrics and liveness/readiness on /healthz
+	// and /readyz.
+	MetricsAddr string
+
+	// ReloadRequestFunc, if set, is called when a "reload" control
+	// command is received, so the caller can re-parse its config file
+	// and send the result on the reload channel passed to Run, the same
+	// as it would for a SIGHUP.
+	ReloadRequestFunc func()
+
+	// StatusUpdateFunc, if set, is called with a short human-readable
+	// phase string ("starting", "scanning", "idle", ...).
+	StatusUpdateFunc func(status string)
+}
+
+func (cfg *Config) setStatus(status string) {
+	if cfg.StatusUpdateFunc != nil {
+		cfg.StatusUpdateFunc(status)
+	}
+}
+
+func (cfg *Config) batchSize() int {
+	if cfg.BatchSize <= 0 {
+		return 100
+	}
+	return cfg.BatchSize
+}
+
+func (cfg *Config) shutdownTimeout() time.Duration {
+	if cfg.ShutdownTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return cfg.ShutdownTimeout
+}
+
+// Run performs the sync. startedFunc is called once the database and RPC
+// connections are open and the first batch is about to begin; it is used
+// by the caller to drop privileges and report readiness.
+//
+// When stop fires, Run stops accepting new batches and waits for the
+// current SQL transaction and any outstanding RPC call to finish on
+// their own, up to cfg.ShutdownTimeout, before returning nil. Run only
+// returns a non-nil error for an unrecoverable failure.
+//
+// Sending a Config on reload applies it to the running loop: the SQL DSN,
+// RPC endpoint/credentials, batch size and poll interval may all be
+// changed this way, reopening the database and/or RPC client only if
+// their parameters changed. A reload that touches Chroot or UID is
+// rejected.
+func Run(cfg Config, stop <-chan struct{}, reload <-chan Config, startedFunc func() error) error {
+	cfg.setStatus("connecting")
+
+	backend, err := getBackend(cfg.Backend)
+	if err != nil {
+		return err
+	}
+
+	db, err := backend.Open(cfg.SQLDSN)
+	if err != nil {
+		return fmt.Errorf("namesync: cannot open database: %v", err)
+	}
+	defer func() { db.Close() }()
+
+	rpc := newRPCClient(cfg.RPCAddress, cfg.RPCUsername, cfg.RPCPassword)
+
+	ctl := newControl()
+	if cfg.ControlSocketPath != "" {
+		ln, err := startControlSocket(cfg.ControlSocketPath, ctl)
+		if err != nil {
+			return fmt.Errorf("namesync: control socket: %v", err)
+		}
+		defer ln.Close()
+	}
+
+	m := newMetrics()
+	if cfg.MetricsAddr != "" {
+		ln, err := startMetricsServer(cfg.MetricsAddr, m)
+		if err != nil {
+			return fmt.Errorf("namesync: metrics listener: %v", err)
+		}
+		defer ln.Close()
+	}
+
+	setStatus := func(status string) {
+		cfg.setStatus(status)
+		ctl.setPhase(status)
+	}
+
+	if err := startedFunc(); err != nil {
+		return err
+	}
+
+	mode := cfg.SyncMode
+	if mode == "" {
+		mode = SyncModeFull
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopping := make(chan struct{})
+	go monitorShutdown(stop, cfg.shutdownTimeout(), stopping, cancel)
+
+	var lastFullSync time.Time
+
+	for {
+		select {
+		case <-stopping:
+			setStatus("stopped")
+			return nil
+		case newCfg := <-reload:
+			cfg, backend, db, rpc = handleReload(cfg, newCfg, backend, db, rpc)
+			continue
+		case <-ctl.reloadRequested:
+			if cfg.ReloadRequestFunc != nil {
+				go cfg.ReloadRequestFunc()
+			}
+			continue
+		case <-ctl.syncNow:
+			// Fall through below to run a batch immediately.
+		default:
+		}
+
+		if ctl.isPaused() {
+			setStatus("paused")
+			select {
+			case <-stopping:
+				setStatus("stopped")
+				return nil
+			case newCfg := <-reload:
+				cfg, backend, db, rpc = handleReload(cfg, newCfg, backend, db, rpc)
+			case <-ctl.reloadRequested:
+				if cfg.ReloadRequestFunc != nil {
+					go cfg.ReloadRequestFunc()
+				}
+			case <-ctl.syncNow:
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		cp, haveCheckpoint, err := backend.LoadCheckpoint(db)
+		if err != nil {
+			setStatus("checkpoint load failed, falling back to full rescan")
+			haveCheckpoint = false
+		}
+
+		doFull := mode == SyncModeFull || !haveCheckpoint
+		if !doFull && cfg.ResyncInterval > 0 && time.Since(lastFullSync) >= cfg.ResyncInterval {
+			doFull = true
+		}
+
+		if doFull {
+			cp = checkpoint{}
+		}
+
+		setStatus("scanning")
+
+		var rows int64
+		cp, rows, err = runOneScan(ctx, backend, db, rpc, &cfg, cp, m, stopping)
+		if err != nil {
+			if ctx.Err() != nil {
+				// The scan was aborted by the shutdown timeout partway through;
+				// the last committed batch's checkpoint is still safe on disk.
+				setStatus("stopped")
+				return nil
+			}
+			return fmt.Errorf("namesync: scan failed: %v", err)
+		}
+
+		ctl.recordSync(cp.BlockHeight, rows)
+
+		tip, tipErr := rpc.blockCount(ctx)
+		if tipErr != nil {
+			recordRPCErr(m, tipErr)
+			tip = cp.BlockHeight
+		}
+		m.recordSync(cp.BlockHeight, tip)
+
+		if doFull {
+			lastFullSync = time.Now()
+		}
+
+		setStatus("idle")
+
+		if cfg.PollInterval <= 0 {
+			// Zero means "scan once and exit"; a one-shot sync that
+			// completes successfully is not a failure.
+			setStatus("scan complete, polling disabled")
+			return nil
+		}
+
+		select {
+		case <-stopping:
+			setStatus("stopped")
+			return nil
+		case newCfg := <-reload:
+			cfg, backend, db, rpc = handleReload(cfg, newCfg, backend, db, rpc)
+		case <-ctl.reloadRequested:
+			if cfg.ReloadRequestFunc != nil {
+				go cfg.ReloadRequestFunc()
+			}
+		case <-ctl.syncNow:
+		case <-time.After(cfg.PollInterval):
+		}
+	}
+}
+
+// monitorShutdown is the single point through which shutdown is signaled
+// to the sync loop. Once stop fires it closes stopping immediately, so
+// Run won't begin a new batch; it then gives the in-flight batch up to
+// timeout to finish on its own before cancelling ctx to force it to
+// abort.
+func monitorShutdown(stop <-chan struct{}, timeout time.Duration, stopping chan struct{}, cancel context.CancelFunc) {
+	<-stop
+	close(stopping)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	<-timer.C
+	cancel()
+}
+
+// runOneScan walks the namespace starting from cp, committing each batch
+// (names + updated checkpoint) as a single transaction, until name_scan
+// reports no further names, ctx is cancelled, or stopping fires. It
+// returns the final checkpoint reached and the number of names written.
+//
+// stopping is checked between batches, not within one: once it fires,
+// runOneScan lets the in-flight batch finish committing and then returns
+// without starting another, so shutdown doesn't have to wait out a full
+// rescan before it stops accepting new work.
+func runOneScan(ctx context.Context, backend Backend, db *sql.DB, rpc *rpcClient, cfg *Config, cp checkpoint, m *metrics, stopping <-chan struct{}) (checkpoint, int64, error) {
+	var rows int64
+
+	for {
+		select {
+		case <-stopping:
+			return cp, rows, nil
+		default:
+		}
+
+		recs, err := rpc.nameScan(ctx, cp.Cursor, cfg.batchSize())
+		if err != nil {
+			recordRPCErr(m, err)
+			return cp, rows, err
+		}
+		if len(recs) == 0 {
+			break
+		}
+
+		height, err := rpc.blockCount(ctx)
+		if err != nil {
+			recordRPCErr(m, err)
+			return cp, rows, err
+		}
+
+		batchStart := time.Now()
+
+		tx, err := backend.BeginBatch(ctx, db)
+		if err != nil {
+			return cp, rows, err
+		}
+
+		var upserted, deleted int
+		for _, rec := range recs {
+			var err error
+			if rec.ExpiresIn <= 0 {
+				err = backend.DeleteName(ctx, tx, rec.Name)
+				deleted++
+			} else {
+				err = backend.UpsertName(ctx, tx, rec)
+				upserted++
+			}
+			if err != nil {
+				tx.Rollback()
+				return cp, rows, err
+			}
+		}
+
+		cp = checkpoint{Cursor: recs[len(recs)-1].Name, BlockHeight: height}
+
+		if err := backend.CommitCheckpoint(ctx, tx, cp); err != nil {
+			tx.Rollback()
+			return cp, rows, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return cp, rows, err
+		}
+
+		// The first batch to commit successfully means names are
+		// actually landing in the database, so /readyz can report
+		// healthy without waiting for the rest of a full rescan.
+		m.setReady(true)
+
+		m.recordBatch(upserted, deleted, time.Since(batchStart))
+
+		rows += int64(len(recs))
+
+		if len(recs) < cfg.batchSize() {
+			break
+		}
+	}
+
+	return cp, rows, nil
+}
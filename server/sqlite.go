@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	RegisterBackend("sqlite", func() Backend { return sqliteBackend{} })
+}
+
+// sqliteBackend targets a local SQLite file, for single-node deployments
+// that don't want to run a separate database server.
+type sqliteBackend struct{}
+
+func (sqliteBackend) Open(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only supports one writer at a time; namesync's batches are
+	// already serialized, so there's no reason to allow more.
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := sqliteEnsureSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func sqliteEnsureSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS names (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			expires_in INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS namesync_state (
+			id INTEGER PRIMARY KEY,
+			cursor TEXT NOT NULL,
+			block_height INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		)
+	`)
+	return err
+}
+
+func (sqliteBackend) BeginBatch(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	return db.BeginTx(ctx, nil)
+}
+
+func (sqliteBackend) UpsertName(ctx context.Context, tx *sql.Tx, rec nameRecord) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO names (name, value, expires_in) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET value = excluded.value, expires_in = excluded.expires_in
+	`, rec.Name, rec.Value, rec.ExpiresIn)
+	return err
+}
+
+func (sqliteBackend) DeleteName(ctx context.Context, tx *sql.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM names WHERE name = ?`, name)
+	return err
+}
+
+func (sqliteBackend) CommitCheckpoint(ctx context.Context, tx *sql.Tx, cp checkpoint) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO namesync_state (id, cursor, block_height, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET cursor = excluded.cursor, block_height = excluded.block_height, updated_at = excluded.updated_at
+	`, checkpointRowID, cp.Cursor, cp.BlockHeight, time.Now().Unix())
+	return err
+}
+
+func (sqliteBackend) LoadCheckpoint(db *sql.DB) (checkpoint, bool, error) {
+	var cp checkpoint
+
+	row := db.QueryRow(`SELECT cursor, block_height FROM namesync_state WHERE id = ?`, checkpointRowID)
+	err := row.Scan(&cp.Cursor, &cp.BlockHeight)
+	if err == sql.ErrNoRows {
+		return checkpoint{}, false, nil
+	}
+	if err != nil {
+		return checkpoint{}, false, err
+	}
+
+	return cp, true, nil
+}
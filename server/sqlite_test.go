@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSQLiteBackendRoundTrip(t *testing.T) {
+	backend := sqliteBackend{}
+
+	db, err := backend.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	if _, ok, err := backend.LoadCheckpoint(db); err != nil {
+		t.Fatalf("LoadCheckpoint on empty db: %v", err)
+	} else if ok {
+		t.Fatal("LoadCheckpoint on empty db: expected ok = false")
+	}
+
+	tx, err := backend.BeginBatch(ctx, db)
+	if err != nil {
+		t.Fatalf("BeginBatch: %v", err)
+	}
+
+	rec := nameRecord{Name: "d/example", Value: `{"ip":"1.2.3.4"}`, ExpiresIn: 1000}
+	if err := backend.UpsertName(ctx, tx, rec); err != nil {
+		t.Fatalf("UpsertName: %v", err)
+	}
+
+	cp := checkpoint{Cursor: rec.Name, BlockHeight: 42}
+	if err := backend.CommitCheckpoint(ctx, tx, cp); err != nil {
+		t.Fatalf("CommitCheckpoint: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit: %v", err)
+	}
+
+	gotCP, ok, err := backend.LoadCheckpoint(db)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+	if !ok {
+		t.Fatal("LoadCheckpoint: expected ok = true after commit")
+	}
+	if gotCP != cp {
+		t.Fatalf("LoadCheckpoint: got %+v, want %+v", gotCP, cp)
+	}
+
+	tx, err = backend.BeginBatch(ctx, db)
+	if err != nil {
+		t.Fatalf("BeginBatch: %v", err)
+	}
+	if err := backend.DeleteName(ctx, tx, rec.Name); err != nil {
+		t.Fatalf("DeleteName: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM names WHERE name = ?`, rec.Name).Scan(&count); err != nil {
+		t.Fatalf("querying names after delete: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("DeleteName: row still present after delete")
+	}
+}